@@ -0,0 +1,121 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvCommand represents one `ENV key=value [key=value ...]` preamble line.
+// Envs returns the raw, unexpanded "key=value" pairs exactly as declared;
+// expansion happens once, in order, when the owning Script resolves its
+// full environment (see resolveEnv).
+type EnvCommand struct {
+	envs []string
+	line int
+}
+
+// Name implements Command.
+func (c *EnvCommand) Name() CmdName { return CmdEnv }
+
+// Line implements Command.
+func (c *EnvCommand) Line() int { return c.line }
+
+func (c *EnvCommand) setLine(n int) { c.line = n }
+
+// Envs returns the raw "key=value" pairs declared on this line.
+func (c *EnvCommand) Envs() []string { return c.envs }
+
+// parseEnvCommand parses the arguments following ENV on a single line.
+// Each argument must look like key=value; var expansion is deferred to
+// resolveEnv so that earlier ENV/ENV_FILE lines are visible by the time a
+// value referencing them is expanded.
+func parseEnvCommand(args []string) (*EnvCommand, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("ENV requires at least one key=value pair")
+	}
+
+	envs := make([]string, 0, len(args))
+	for _, arg := range args {
+		key, _, ok := splitKeyValue(arg)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("ENV: malformed key=value pair %q", arg)
+		}
+		envs = append(envs, arg)
+	}
+
+	return &EnvCommand{envs: envs}, nil
+}
+
+func splitKeyValue(s string) (key, value string, ok bool) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	key, value = s[:i], s[i+1:]
+	for _, r := range key {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return "", "", false
+		}
+	}
+	return key, value, true
+}
+
+// resolveEnv walks the script's preambles in declaration order, expanding
+// and merging ENV/ENV_FILE values into scope. Earlier declarations are
+// visible to later ones; a later declaration of the same key overwrites an
+// earlier one (ENV_FILE's override=true is the one case where the process
+// environment instead wins, handled in resolveEnvFile).
+func resolveEnv(order []Command) (map[string]string, error) {
+	scope := map[string]string{}
+
+	for _, cmd := range order {
+		switch c := cmd.(type) {
+		case *EnvCommand:
+			for _, raw := range c.envs {
+				key, value, _ := splitKeyValue(raw)
+				expanded, err := expandEnv(value, scope)
+				if err != nil {
+					return nil, fmt.Errorf("%d: ENV %s: %w", c.line, raw, err)
+				}
+				scope[key] = expanded
+			}
+		case *EnvFileCommand:
+			if err := resolveEnvFile(c, scope); err != nil {
+				return nil, fmt.Errorf("%d: %w", c.line, err)
+			}
+		}
+	}
+
+	return scope, nil
+}
+
+func resolveEnvFile(c *EnvFileCommand, scope map[string]string) error {
+	entries, err := parseDotEnvFile(c.path)
+	if err != nil {
+		return fmt.Errorf("ENV_FILE %s: %w", c.path, err)
+	}
+
+	for _, entry := range entries {
+		if c.override {
+			if v, ok := os.LookupEnv(entry.key); ok {
+				scope[entry.key] = v
+				continue
+			}
+		}
+		if !entry.expand {
+			scope[entry.key] = entry.value
+			continue
+		}
+		expanded, err := expandEnv(entry.value, scope)
+		if err != nil {
+			return fmt.Errorf("ENV_FILE %s: %s: %w", c.path, entry.key, err)
+		}
+		scope[entry.key] = expanded
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestEnvFile(t *testing.T, dir string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ".env")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandENVFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashd-envfile-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("basic KEY=VALUE with comments and export", func(t *testing.T) {
+		path := writeTestEnvFile(t, dir, "# a comment\nexport FOO=bar\nBAZ=\"qux\"\n\nQUOTED='literal $FOO'\n")
+		s, err := Parse("t.star", strings.NewReader(fmt.Sprintf("ENV_FILE %s", path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Env["FOO"] != "bar" || s.Env["BAZ"] != "qux" || s.Env["QUOTED"] != "literal $FOO" {
+			t.Fatalf("unexpected env %#v", s.Env)
+		}
+	})
+
+	t.Run("ENV_FILE precedes ENV, ENV wins on overlap", func(t *testing.T) {
+		path := writeTestEnvFile(t, dir, "FOO=from-file\n")
+		s, err := Parse("t.star", strings.NewReader(fmt.Sprintf("ENV_FILE %s\nENV FOO=from-env", path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Env["FOO"] != "from-env" {
+			t.Fatalf("expected ENV to win over ENV_FILE, got %q", s.Env["FOO"])
+		}
+	})
+
+	t.Run("override=true prefers process env", func(t *testing.T) {
+		os.Setenv("CRASHD_TEST_ENVFILE_FOO", "from-process")
+		defer os.Unsetenv("CRASHD_TEST_ENVFILE_FOO")
+
+		path := writeTestEnvFile(t, dir, "CRASHD_TEST_ENVFILE_FOO=from-file\n")
+		s, err := Parse("t.star", strings.NewReader(fmt.Sprintf("ENV_FILE %s override=true", path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Env["CRASHD_TEST_ENVFILE_FOO"] != "from-process" {
+			t.Fatalf("expected override=true to prefer process env, got %q", s.Env["CRASHD_TEST_ENVFILE_FOO"])
+		}
+	})
+
+	t.Run("missing file errors", func(t *testing.T) {
+		_, err := Parse("t.star", strings.NewReader("ENV_FILE /does/not/exist"))
+		if err == nil {
+			t.Fatal("expected an error for a missing ENV_FILE")
+		}
+	})
+
+	t.Run("malformed line errors with file:line context", func(t *testing.T) {
+		path := writeTestEnvFile(t, dir, "FOO=bar\nnotkeyvalue\n")
+		_, err := Parse("t.star", strings.NewReader(fmt.Sprintf("ENV_FILE %s", path)))
+		if err == nil {
+			t.Fatal("expected an error for a malformed dotenv line")
+		}
+	})
+
+	t.Run("unrecognized flag errors", func(t *testing.T) {
+		path := writeTestEnvFile(t, dir, "FOO=bar\n")
+		_, err := Parse("t.star", strings.NewReader(fmt.Sprintf("ENV_FILE %s overide=true", path)))
+		if err == nil {
+			t.Fatal("expected an error for an unrecognized ENV_FILE flag")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvFileCommand represents one `ENV_FILE path/to/file [override=true]`
+// preamble line.
+type EnvFileCommand struct {
+	path     string
+	override bool
+	line     int
+}
+
+// Name implements Command.
+func (c *EnvFileCommand) Name() CmdName { return CmdEnvFile }
+
+// Line implements Command.
+func (c *EnvFileCommand) Line() int { return c.line }
+
+func (c *EnvFileCommand) setLine(n int) { c.line = n }
+
+// Path returns the dotenv file path as declared.
+func (c *EnvFileCommand) Path() string { return c.path }
+
+// Override reports whether matching process environment variables win over
+// values declared in the file.
+func (c *EnvFileCommand) Override() bool { return c.override }
+
+// parseEnvFileCommand parses the arguments following ENV_FILE on a single
+// line: a required path, and an optional override=true/false flag.
+func parseEnvFileCommand(args []string) (*EnvFileCommand, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("ENV_FILE requires a file path")
+	}
+
+	cmd := &EnvFileCommand{path: args[0]}
+	for _, arg := range args[1:] {
+		key, value, ok := splitKeyValue(arg)
+		if !ok {
+			return nil, fmt.Errorf("ENV_FILE: malformed argument %q", arg)
+		}
+		if key != "override" {
+			return nil, fmt.Errorf("ENV_FILE: unrecognized flag %q", arg)
+		}
+		cmd.override = value == "true"
+	}
+
+	return cmd, nil
+}
+
+type dotEnvEntry struct {
+	key    string
+	value  string
+	expand bool // false for single-quoted values, which are taken literally
+}
+
+// parseDotEnvFile reads a dotenv-style file: blank lines and lines
+// starting with '#' are ignored, KEY=VALUE pairs may be prefixed with
+// "export ", and values may be quoted (with \" and \\ escapes honored
+// inside double quotes; single-quoted values are taken literally, including
+// suppressing $VAR expansion, per POSIX/dotenv convention).
+func parseDotEnvFile(path string) ([]dotEnvEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []dotEnvEntry
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := splitKeyValue(line)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: malformed line %q", path, lineNum, line)
+		}
+
+		wasSingleQuoted := len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\''
+		value, err := unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+
+		entries = append(entries, dotEnvEntry{key: key, value: value, expand: !wasSingleQuoted})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func unquote(s string) (string, error) {
+	if len(s) < 2 {
+		return s, nil
+	}
+
+	switch {
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	case s[0] == '"' && s[len(s)-1] == '"':
+		inner := s[1 : len(s)-1]
+		var out strings.Builder
+		for i := 0; i < len(inner); i++ {
+			if inner[i] == '\\' && i+1 < len(inner) {
+				i++
+				switch inner[i] {
+				case 'n':
+					out.WriteByte('\n')
+				case 't':
+					out.WriteByte('\t')
+				default:
+					out.WriteByte(inner[i])
+				}
+				continue
+			}
+			out.WriteByte(inner[i])
+		}
+		return out.String(), nil
+	default:
+		return s, nil
+	}
+}
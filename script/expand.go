@@ -0,0 +1,129 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandEnv expands $VAR and ${VAR}, ${VAR:-default}, ${VAR:?message}
+// references in s against scope, falling back to the process environment
+// for names scope doesn't define. It does not re-expand its own output, so
+// "recursive" expansion across multiple ENV lines comes from resolveEnv
+// calling this once per line with the scope built up so far, not from
+// expandEnv reparsing an already-substituted value.
+func expandEnv(s string, scope map[string]string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '$' || i == len(s)-1 {
+			out.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := matchingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${...} in %q", s)
+			}
+			expr := s[i+2 : end]
+			val, err := expandBraced(expr, scope)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(val)
+			i = end
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isIdentByte(s[j], j == i+1) {
+			j++
+		}
+		if j == i+1 {
+			// Bare '$' not followed by an identifier; leave it as-is.
+			out.WriteByte(c)
+			continue
+		}
+		out.WriteString(lookupEnv(s[i+1:j], scope))
+		i = j - 1
+	}
+	return out.String(), nil
+}
+
+// matchingBrace returns the index of the '}' matching the '{' implicitly
+// opened just before start, accounting for nested ${...} references (e.g.
+// the default in ${UNSET:-${FALLBACK}}), or -1 if unterminated.
+func matchingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && i > start && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isIdentByte reports whether b can appear in a $NAME reference. first
+// marks the leading character, which (like a Starlark/shell identifier)
+// may not be a digit.
+func isIdentByte(b byte, first bool) bool {
+	if b >= '0' && b <= '9' {
+		return !first
+	}
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func lookupEnv(name string, scope map[string]string) string {
+	if v, ok := scope[name]; ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// expandBraced handles the body of a ${...} reference: NAME, NAME:-default
+// or NAME:?message.
+func expandBraced(expr string, scope map[string]string) (string, error) {
+	name := expr
+	op := ""
+	arg := ""
+
+	if i := strings.IndexAny(expr, ":"); i >= 0 && i+1 < len(expr) {
+		name = expr[:i]
+		op = string(expr[i+1])
+		arg = expr[i+2:]
+	}
+
+	value, set := scope[name]
+	if !set {
+		value, set = os.LookupEnv(name)
+	}
+
+	switch op {
+	case "-":
+		if !set || value == "" {
+			return expandEnv(arg, scope)
+		}
+		return value, nil
+	case "?":
+		if !set || value == "" {
+			msg := arg
+			if msg == "" {
+				msg = fmt.Sprintf("%s: not set", name)
+			}
+			return "", fmt.Errorf("%s", msg)
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestParseRecursiveExpansion(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV FIRST=hello\nENV SECOND=${FIRST}-world\nENV THIRD=$SECOND!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["THIRD"] != "hello-world!" {
+		t.Fatalf("expected recursive expansion across ENV lines, got %q", s.Env["THIRD"])
+	}
+}
+
+func TestParseExpansionFallsBackToProcessEnv(t *testing.T) {
+	os.Setenv("CRASHD_TEST_EXPAND_FOO", "from-process")
+	defer os.Unsetenv("CRASHD_TEST_EXPAND_FOO")
+
+	s, err := Parse("t.star", strings.NewReader("ENV BAR=${CRASHD_TEST_EXPAND_FOO}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["BAR"] != "from-process" {
+		t.Fatalf("expected fallback to process env, got %q", s.Env["BAR"])
+	}
+}
+
+func TestParseExpansionDefaultAndError(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV BAR=${UNSET_VAR:-fallback}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["BAR"] != "fallback" {
+		t.Fatalf("expected default value, got %q", s.Env["BAR"])
+	}
+
+	_, err = Parse("t.star", strings.NewReader("ENV BAR=${UNSET_VAR:?must be set}"))
+	if err == nil {
+		t.Fatal("expected an error for an unset required variable")
+	}
+	wantErr := "t.star:1: ENV BAR=${UNSET_VAR:?must be set}: must be set"
+	if err.Error() != wantErr {
+		t.Fatalf("expected error %q, got %q", wantErr, err.Error())
+	}
+}
+
+func TestParseExpansionDefaultAndErrorMessagesWithSpaces(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV BAR=${UNSET_VAR:-hello world}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["BAR"] != "hello world" {
+		t.Fatalf("expected default containing a space to expand whole, got %q", s.Env["BAR"])
+	}
+
+	_, err = Parse("t.star", strings.NewReader("ENV BAR=${UNSET_VAR:?must be set}"))
+	if err == nil {
+		t.Fatal("expected an error for an unset required variable")
+	}
+	if !strings.Contains(err.Error(), "must be set") {
+		t.Fatalf("expected the full (space-containing) error message to survive parsing, got %q", err.Error())
+	}
+}
+
+func TestParseExpansionLeavesLeadingDigitsLiteral(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV MSG=Cost:$100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["MSG"] != "Cost:$100" {
+		t.Fatalf("expected a digit-led token to be left literal, got %q", s.Env["MSG"])
+	}
+}
+
+func TestParseExpansionNestedBraceDefault(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV FALLBACK=fb\nENV BAR=${UNSET:-${FALLBACK}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["BAR"] != "fb" {
+		t.Fatalf("expected nested ${...} default to expand, got %q", s.Env["BAR"])
+	}
+}
+
+func TestStarlarkEnvIsFrozen(t *testing.T) {
+	s, err := Parse("t.star", strings.NewReader("ENV FOO=bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dict := s.StarlarkEnv()
+	if err := dict.SetKey(starlark.String("FOO"), starlark.String("mutated")); err == nil {
+		t.Fatal("expected StarlarkEnv() to return a frozen dict")
+	}
+	v, found, err := dict.Get(starlark.String("FOO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || v.(starlark.String) != "bar" {
+		t.Fatalf("expected env[FOO] = bar, got %v (found=%v)", v, found)
+	}
+}
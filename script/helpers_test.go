@@ -0,0 +1,40 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+// commandTest describes a single preamble parse scenario: source produces
+// the script text to parse, script asserts against the parsed result, and
+// shouldFail marks cases where Parse is expected to return an error.
+type commandTest struct {
+	name       string
+	source     func() string
+	script     func(s *Script) error
+	shouldFail bool
+}
+
+func runCommandTest(t *testing.T, test commandTest) {
+	t.Helper()
+
+	s, err := Parse(test.name, strings.NewReader(test.source()))
+	if test.shouldFail {
+		if err == nil {
+			t.Fatal("expected an error, got none")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if test.script != nil {
+		if err := test.script(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
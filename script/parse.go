@@ -0,0 +1,92 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parse reads a crash-diagnostics script's preamble from source (ENV and
+// ENV_FILE lines, blank lines, and '#' comments) and returns the parsed
+// Script with its Env fully resolved. name is used to prefix parse errors
+// with file:line context.
+func Parse(name string, source io.Reader) (*Script, error) {
+	s := newScript()
+
+	scanner := bufio.NewScanner(source)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitPreambleFields(line)
+		keyword, args := fields[0], fields[1:]
+
+		var cmd Command
+		var err error
+		switch CmdName(keyword) {
+		case CmdEnv:
+			cmd, err = parseEnvCommand(args)
+		case CmdEnvFile:
+			cmd, err = parseEnvFileCommand(args)
+		default:
+			err = fmt.Errorf("unknown preamble command %q", keyword)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNum, err)
+		}
+		cmd.setLine(lineNum)
+		s.add(cmd)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	env, err := resolveEnv(s.Order)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", name, err)
+	}
+	s.Env = env
+
+	return s, nil
+}
+
+// splitPreambleFields splits a preamble line on whitespace, like
+// strings.Fields, but treats a ${...} reference as an opaque unit so a
+// default or error message inside it (e.g. ${VAR:?must be set}) isn't torn
+// apart at its spaces. Nesting (${OUTER:-${INNER}}) is tracked the same way
+// matchingBrace does in expand.go.
+func splitPreambleFields(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '$' && i+1 < len(line) && line[i+1] == '{':
+			depth++
+			cur.WriteByte(c)
+		case c == '}' && depth > 0:
+			depth--
+			cur.WriteByte(c)
+		case (c == ' ' || c == '\t') && depth == 0:
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package script parses the preamble section of a crash-diagnostics script
+// (ENV, ENV_FILE, ...) that precedes its Starlark body.
+package script
+
+import (
+	"go.starlark.net/starlark"
+)
+
+// CmdName identifies the kind of a preamble command.
+type CmdName string
+
+const (
+	// CmdEnv is the command name for ENV preamble lines.
+	CmdEnv CmdName = "ENV"
+	// CmdEnvFile is the command name for ENV_FILE preamble lines.
+	CmdEnvFile CmdName = "ENV_FILE"
+)
+
+// Command is implemented by every preamble command.
+type Command interface {
+	Name() CmdName
+
+	// Line returns the 1-based source line the command was declared on,
+	// so later stages (e.g. resolveEnv) can still report file:line
+	// context once commands have been flattened into Script.Order.
+	Line() int
+
+	setLine(n int)
+}
+
+// Script is the parsed result of a crash-diagnostics script's preamble.
+// Preambles groups commands by kind for callers that only care about one
+// kind (e.g. s.Preambles[CmdEnv]); Order preserves the sequence commands
+// appeared in across kinds, which env resolution depends on.
+type Script struct {
+	Preambles map[CmdName][]Command
+	Order     []Command
+
+	// Env is the fully resolved environment: ENV_FILE entries and ENV
+	// assignments merged and expanded in declaration order.
+	Env map[string]string
+}
+
+func newScript() *Script {
+	return &Script{
+		Preambles: map[CmdName][]Command{},
+		Env:       map[string]string{},
+	}
+}
+
+func (s *Script) add(cmd Command) {
+	s.Preambles[cmd.Name()] = append(s.Preambles[cmd.Name()], cmd)
+	s.Order = append(s.Order, cmd)
+}
+
+// StarlarkEnv exposes Env as a frozen Starlark dict named "env", so a
+// script body can do ssh_config(username=env["SSH_USER"]) instead of
+// hard-coding values that came from ENV/ENV_FILE.
+func (s *Script) StarlarkEnv() *starlark.Dict {
+	dict := starlark.NewDict(len(s.Env))
+	for k, v := range s.Env {
+		// Env only ever holds values produced by this package's own
+		// expansion, so the error case here (duplicate/unhashable key)
+		// can't happen.
+		_ = dict.SetKey(starlark.String(k), starlark.String(v))
+	}
+	dict.Freeze()
+	return dict
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+// archiveEntryWriter abstracts over the tar.gz and zip encodings so
+// CopyFromArchive can stream remote files into either without duplicating
+// the sftp-walking logic. size is the caller's already-known file size
+// (from an sftp Stat), so implementations can stream via io.Copy instead of
+// buffering the whole file in memory.
+type archiveEntryWriter interface {
+	WriteFile(name string, mode os.FileMode, size int64, r io.Reader) error
+	Close() error
+}
+
+type tarGzEntryWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarGzEntryWriter(w io.Writer, level int) (*tarGzEntryWriter, error) {
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &tarGzEntryWriter{gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+func (a *tarGzEntryWriter) WriteFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	if err := a.tw.WriteHeader(&tar.Header{Name: name, Mode: int64(mode.Perm()), Size: size}); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tw, r)
+	return err
+}
+
+func (a *tarGzEntryWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+type zipEntryWriter struct {
+	zw *zip.Writer
+}
+
+func newZipEntryWriter(w io.Writer) *zipEntryWriter {
+	return &zipEntryWriter{zw: zip.NewWriter(w)}
+}
+
+func (a *zipEntryWriter) WriteFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	header.SetMode(mode)
+	entry, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, r)
+	return err
+}
+
+func (a *zipEntryWriter) Close() error {
+	return a.zw.Close()
+}
+
+// CopyFromArchive streams the files on the remote host matched by
+// remotePath directly into an archive at archivePath (format "tgz" or
+// "zip", compress level 0-9, only meaningful for "tgz"), without writing a
+// scratch directory tree to disk first.
+func CopyFromArchive(args SSHArgs, remotePath, archivePath, format string, level int) error {
+	client, err := NewClient(args)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client.client)
+	if err != nil {
+		return fmt.Errorf("ssh: failed to start sftp session: %w", err)
+	}
+	defer sftpClient.Close()
+
+	matches, err := sftpClient.Glob(remotePath)
+	if err != nil {
+		return fmt.Errorf("ssh: failed to glob %s: %w", remotePath, err)
+	}
+	if len(matches) == 0 {
+		matches = []string{remotePath}
+	}
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("ssh: failed to create %s: %w", archivePath, err)
+	}
+	defer out.Close()
+
+	var writer archiveEntryWriter
+	switch format {
+	case "zip":
+		writer = newZipEntryWriter(out)
+	case "tgz", "":
+		writer, err = newTarGzEntryWriter(out, level)
+		if err != nil {
+			return fmt.Errorf("ssh: failed to start gzip writer: %w", err)
+		}
+	default:
+		return fmt.Errorf("ssh: unsupported compress_format %q", format)
+	}
+
+	for _, remote := range matches {
+		info, err := sftpClient.Stat(remote)
+		if err != nil {
+			return fmt.Errorf("ssh: failed to stat %s: %w", remote, err)
+		}
+
+		srcFile, err := sftpClient.Open(remote)
+		if err != nil {
+			return fmt.Errorf("ssh: failed to open %s: %w", remote, err)
+		}
+
+		err = writer.WriteFile(remote, info.Mode(), info.Size(), srcFile)
+		srcFile.Close()
+		if err != nil {
+			return fmt.Errorf("ssh: failed to archive %s: %w", remote, err)
+		}
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+)
+
+// CopyFrom copies the remote file (or files matched by a glob in remotePath)
+// from the host identified by args into localDir, preserving the remote
+// relative path under localDir. It returns the list of local paths written.
+func CopyFrom(args SSHArgs, remotePath, localDir string) ([]string, error) {
+	client, err := NewClient(args)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client.client)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to start sftp session: %w", err)
+	}
+	defer sftpClient.Close()
+
+	matches, err := sftpClient.Glob(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to glob %s: %w", remotePath, err)
+	}
+	if len(matches) == 0 {
+		matches = []string{remotePath}
+	}
+
+	var copied []string
+	for _, remote := range matches {
+		dest := filepath.Join(localDir, remote)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return copied, fmt.Errorf("ssh: failed to create %s: %w", filepath.Dir(dest), err)
+		}
+
+		info, err := sftpClient.Stat(remote)
+		if err != nil {
+			return copied, fmt.Errorf("ssh: failed to stat %s: %w", remote, err)
+		}
+
+		srcFile, err := sftpClient.Open(remote)
+		if err != nil {
+			return copied, fmt.Errorf("ssh: failed to open %s: %w", remote, err)
+		}
+
+		dstFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+		if err != nil {
+			srcFile.Close()
+			return copied, fmt.Errorf("ssh: failed to create %s: %w", dest, err)
+		}
+
+		_, copyErr := io.Copy(dstFile, srcFile)
+		srcFile.Close()
+		dstFile.Close()
+		if copyErr != nil {
+			return copied, fmt.Errorf("ssh: failed to copy %s: %w", remote, copyErr)
+		}
+
+		copied = append(copied, dest)
+	}
+
+	return copied, nil
+}
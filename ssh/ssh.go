@@ -0,0 +1,106 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ssh provides a thin wrapper around golang.org/x/crypto/ssh used by
+// crash-diagnostics to connect to remote hosts in order to run commands and
+// copy files.
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHArgs collects the parameters needed to establish a connection to a
+// remote host.
+type SSHArgs struct {
+	User           string
+	Host           string
+	Port           string
+	PrivateKeyPath string
+	// PrivateKeyPassphrase decrypts PrivateKeyPath when the key is
+	// encrypted. Left nil/empty for unencrypted keys.
+	PrivateKeyPassphrase []byte
+	MaxRetries           int
+}
+
+// Client wraps an established SSH connection to a single remote host.
+type Client struct {
+	args   SSHArgs
+	client *ssh.Client
+}
+
+// NewClient dials the host described by args, retrying up to args.MaxRetries
+// times before giving up.
+func NewClient(args SSHArgs) (*Client, error) {
+	key, err := ioutil.ReadFile(args.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to read private key: %w", err)
+	}
+	var signer ssh.Signer
+	if len(args.PrivateKeyPassphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, args.PrivateKeyPassphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to parse private key: %w", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            args.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+
+	addr := net.JoinHostPort(args.Host, args.Port)
+
+	retries := args.MaxRetries
+	if retries < 1 {
+		retries = 1
+	}
+
+	var lastErr error
+	for i := 0; i < retries; i++ {
+		conn, err := ssh.Dial("tcp", addr, config)
+		if err == nil {
+			return &Client{args: args, client: conn}, nil
+		}
+		lastErr = err
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("ssh: failed to dial %s after %d attempt(s): %w", addr, retries, lastErr)
+}
+
+// Close releases the underlying connection.
+func (c *Client) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// NewSession opens a new SSH session on the connection.
+func (c *Client) NewSession() (*ssh.Session, error) {
+	return c.client.NewSession()
+}
+
+// Exec runs cmd on the remote host and returns its combined output.
+func (c *Client) Exec(cmd string) (string, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("ssh: failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	out, err := session.CombinedOutput(cmd)
+	if err != nil {
+		return string(out), fmt.Errorf("ssh: command %q failed: %w", cmd, err)
+	}
+	return string(out), nil
+}
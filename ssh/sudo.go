@@ -0,0 +1,105 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sudoPasswordPrompt is the substring golang's ssh package sees on the
+// session's combined output stream right before sudo blocks waiting for a
+// password on its pty.
+const sudoPasswordPrompt = "password for"
+
+// RunSudo runs cmd as root on the host described by args, prefixing it with
+// `sudo -S -p`, allocating a pty, and feeding sudoPassword to the remote
+// prompt the moment it appears on the output stream.
+func RunSudo(args SSHArgs, cmd string, sudoPassword []byte) (string, error) {
+	client, err := NewClient(args)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("ssh: failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	modes := ssh.TerminalModes{ssh.ECHO: 0}
+	if err := session.RequestPty("xterm", 80, 40, modes); err != nil {
+		return "", fmt.Errorf("ssh: failed to request pty for sudo: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("ssh: failed to open stdin pipe: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("ssh: failed to open stdout pipe: %w", err)
+	}
+
+	sudoCmd := fmt.Sprintf("sudo -S -p %q %s", sudoPasswordPrompt+":", cmd)
+	if err := session.Start(sudoCmd); err != nil {
+		return "", fmt.Errorf("ssh: failed to start sudo command: %w", err)
+	}
+
+	output, err := feedSudoPassword(stdout, stdin, sudoPassword)
+	if err != nil {
+		return output, err
+	}
+
+	if err := session.Wait(); err != nil {
+		return output, fmt.Errorf("ssh: sudo command failed: %w", err)
+	}
+
+	return output, nil
+}
+
+// feedSudoPassword copies stdout to its return value, writing password (plus
+// a trailing newline) to stdin the moment sudoPasswordPrompt appears.
+//
+// The prompt ("password for ...:") is never newline-terminated -- sudo
+// blocks on stdin right after printing it -- so this scans byte-by-byte
+// rather than using bufio.Scanner/ReadString('\n'), which would block
+// forever waiting for a newline that never comes.
+func feedSudoPassword(stdout io.Reader, stdin io.Writer, password []byte) (string, error) {
+	var output bytes.Buffer
+	var pending bytes.Buffer // unterminated line, reset on '\n' or once the prompt is matched
+	sentPassword := false
+	buf := make([]byte, 1)
+
+	for {
+		n, rerr := stdout.Read(buf)
+		if n > 0 {
+			b := buf[0]
+			output.WriteByte(b)
+			pending.WriteByte(b)
+
+			if !sentPassword && strings.Contains(pending.String(), sudoPasswordPrompt) {
+				if _, werr := stdin.Write(append(password, '\n')); werr != nil {
+					return output.String(), fmt.Errorf("ssh: failed to write sudo password: %w", werr)
+				}
+				sentPassword = true
+				pending.Reset()
+			} else if b == '\n' {
+				pending.Reset()
+			}
+		}
+
+		if rerr == io.EOF {
+			return output.String(), nil
+		}
+		if rerr != nil {
+			return output.String(), fmt.Errorf("ssh: failed reading sudo output: %w", rerr)
+		}
+	}
+}
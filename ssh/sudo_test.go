@@ -0,0 +1,86 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// TestFeedSudoPasswordUnterminatedPrompt reproduces the real sudo prompt
+// shape: "password for ...:" is written with no trailing newline, and the
+// remote process then blocks waiting on stdin. feedSudoPassword must notice
+// the prompt without waiting for a newline that will never arrive.
+func TestFeedSudoPasswordUnterminatedPrompt(t *testing.T) {
+	stdoutR, stdoutW := io.Pipe()
+	stdinR, stdinW := io.Pipe()
+
+	go func() {
+		stdoutW.Write([]byte("[sudo] password for test:"))
+		stdoutW.Close()
+	}()
+
+	done := make(chan struct{})
+	var output string
+	var err error
+	go func() {
+		output, err = feedSudoPassword(stdoutR, stdinW, []byte("sekret"))
+		close(done)
+	}()
+
+	buf := make([]byte, 64)
+	n, rerr := stdinR.Read(buf)
+	if rerr != nil {
+		t.Fatalf("expected the password to be written to stdin, got error: %s", rerr)
+	}
+	if got := string(buf[:n]); got != "sekret\n" {
+		t.Fatalf("expected password %q to be written to stdin, got %q", "sekret\n", got)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("feedSudoPassword did not return after stdout closed; it likely blocked waiting for a newline")
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if output != "[sudo] password for test:" {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestFeedSudoPasswordAlreadyLineBuffered(t *testing.T) {
+	stdoutR, stdoutW := io.Pipe()
+	stdinR, stdinW := io.Pipe()
+
+	go func() {
+		stdoutW.Write([]byte("some preamble\n"))
+		stdoutW.Write([]byte("password for test:"))
+		stdoutW.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		feedSudoPassword(stdoutR, stdinW, []byte("sekret"))
+		close(done)
+	}()
+
+	buf := make([]byte, 64)
+	n, err := stdinR.Read(buf)
+	if err != nil {
+		t.Fatalf("expected the password to be written to stdin, got error: %s", err)
+	}
+	if got := string(buf[:n]); got != "sekret\n" {
+		t.Fatalf("unexpected stdin write %q", got)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("feedSudoPassword did not return")
+	}
+}
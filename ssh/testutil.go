@@ -0,0 +1,44 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ssh
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// MakeRemoteTestSSHFile writes content to path on the host described by args.
+// It is a test helper used by integration tests that require a reachable
+// sshd (see the *_test.go files under starlark/).
+func MakeRemoteTestSSHFile(t *testing.T, args SSHArgs, path, content string) {
+	t.Helper()
+
+	client, err := NewClient(args)
+	if err != nil {
+		t.Fatalf("ssh: MakeRemoteTestSSHFile: %s", err)
+	}
+	defer client.Close()
+
+	dir := filepath.Dir(path)
+	cmd := fmt.Sprintf("mkdir -p %s && printf '%%s' \"$(cat <<'EOF'\n%s\nEOF\n)\" > %s", dir, content, path)
+	if _, err := client.Exec(cmd); err != nil {
+		t.Fatalf("ssh: MakeRemoteTestSSHFile: %s", err)
+	}
+}
+
+// RemoveRemoteTestSSHFile removes path from the host described by args.
+func RemoveRemoteTestSSHFile(t *testing.T, args SSHArgs, path string) {
+	t.Helper()
+
+	client, err := NewClient(args)
+	if err != nil {
+		t.Fatalf("ssh: RemoveRemoteTestSSHFile: %s", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Exec(fmt.Sprintf("rm -f %s", path)); err != nil {
+		t.Fatalf("ssh: RemoveRemoteTestSSHFile: %s", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// archiveFunc implements the Starlark archive(sources=[...], output=...)
+// builtin. Each source is either a plain file/directory (walked and added
+// under its base name) or a per-host archive produced by
+// copy_from(compress=True) in either "tgz" or "zip" form (re-read and its
+// entries re-homed under the archive's base name, so a bug report attaches
+// one top-level tar.gz instead of one per host).
+func archiveFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		sources *starlark.List
+		output  starlark.String
+	)
+
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "sources", &sources, "output?", &output); err != nil {
+		return nil, err
+	}
+
+	outPath := string(output)
+	if outPath == "" {
+		outPath = filepath.Join(defaults.workdir, "crashd-archive.tar.gz")
+	}
+
+	var paths []string
+	if sources != nil {
+		iter := sources.Iterate()
+		defer iter.Done()
+		var val starlark.Value
+		for iter.Next(&val) {
+			paths = append(paths, asString(val))
+		}
+	}
+
+	if err := buildArchive(outPath, paths); err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	return starlark.String(outPath), nil
+}
+
+// buildArchive writes a single tar.gz at outPath containing every source
+// path, each re-homed under filepath.Base(source) (minus its .tar.gz, .tgz
+// or .zip suffix when source is itself a per-host archive).
+func buildArchive(outPath string, sources []string) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for _, src := range sources {
+		switch {
+		case isTarGz(src):
+			if err := addArchiveEntries(tw, src); err != nil {
+				return err
+			}
+		case isZip(src):
+			if err := addZipEntries(tw, src); err != nil {
+				return err
+			}
+		default:
+			if err := addPathEntries(tw, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+func isZip(path string) bool {
+	return strings.HasSuffix(path, ".zip")
+}
+
+// addArchiveEntries re-homes the contents of a per-host tar.gz (as produced
+// by copy_from(compress=True)) under a top-level directory named after the
+// archive's base name, so multiple hosts' archives don't collide once
+// merged into one bundle.
+func addArchiveEntries(tw *tar.Writer, archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid tar.gz archive: %w", archivePath, err)
+	}
+	defer gzr.Close()
+
+	prefix := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(archivePath), ".tar.gz"), ".tgz")
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = filepath.Join(prefix, hdr.Name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addZipEntries re-homes the contents of a per-host zip (as produced by
+// copy_from(compress=True, compress_format="zip")) under a top-level
+// directory named after the archive's base name, the same way
+// addArchiveEntries does for a per-host tar.gz.
+func addZipEntries(tw *tar.Writer, archivePath string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid zip archive: %w", archivePath, err)
+	}
+	defer zr.Close()
+
+	prefix := strings.TrimSuffix(filepath.Base(archivePath), ".zip")
+
+	for _, zf := range zr.File {
+		hdr, err := tar.FileInfoHeader(zf.FileInfo(), "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.Join(prefix, zf.Name)
+
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addPathEntries walks a plain file or directory and adds it to tw under
+// its own base name.
+func addPathEntries(tw *tar.Writer, path string) error {
+	base := filepath.Base(path)
+
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(base, rel)
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildArchiveMergesPlainAndPerHostArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashd-archive-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A plain directory, as captured by copy_from() without compress=True.
+	plainDir := filepath.Join(dir, "host-a")
+	if err := os.MkdirAll(plainDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(plainDir, "foo.txt"), []byte("FooBar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A per-host tar.gz, as produced by copy_from(compress=True).
+	hostBArchive := filepath.Join(dir, "host-b.tar.gz")
+	if err := buildArchive(hostBArchive, []string{plainDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "bundle.tar.gz")
+	if err := buildArchive(out, []string{plainDir, hostBArchive}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gzr.Close()
+
+	names := map[string]bool{}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[hdr.Name] = true
+	}
+
+	if !names[filepath.Join("host-a", "foo.txt")] {
+		t.Errorf("expected bundle to contain host-a/foo.txt, got %v", names)
+	}
+	if !names[filepath.Join("host-b", "host-a", "foo.txt")] {
+		t.Errorf("expected bundle to re-home host-b.tar.gz's entries under host-b/, got %v", names)
+	}
+}
+
+func TestBuildArchiveMergesPerHostZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashd-archive-zip-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A per-host zip, as produced by copy_from(compress=True, compress_format="zip").
+	hostArchive := filepath.Join(dir, "host-c.zip")
+	zf, err := os.Create(hostArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	entry, err := zw.Create("foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := entry.Write([]byte("FooBar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zf.Close()
+
+	out := filepath.Join(dir, "bundle.tar.gz")
+	if err := buildArchive(out, []string{hostArchive}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gzr.Close()
+
+	names := map[string]bool{}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[hdr.Name] = true
+	}
+
+	if !names[filepath.Join("host-c", "foo.txt")] {
+		t.Errorf("expected bundle to re-home host-c.zip's entries under host-c/, got %v", names)
+	}
+}
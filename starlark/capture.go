@@ -0,0 +1,83 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+// captureFunc implements the Starlark capture(cmd, resources=[...]) builtin.
+// It runs cmd on each resource concurrently (same fan-out/ordering/fail_fast
+// semantics as copy_from) and writes the combined output of each host to
+// <workdir>/<resource>/<sanitized cmd>.txt, returning that path as result.
+func captureFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		cmd           starlark.String
+		resourcesList *starlark.List
+		failFast      starlark.Bool
+	)
+
+	if err := starlark.UnpackArgs(
+		b.Name(), args, kwargs,
+		"cmd?", &cmd,
+		"resources?", &resourcesList,
+		"fail_fast?", &failFast,
+	); err != nil {
+		return nil, err
+	}
+
+	if resourcesList == nil || resourcesList.Len() == 0 {
+		return nil, fmt.Errorf("%s: missing required resources", b.Name())
+	}
+
+	results, err := runOverResources(resourcesList, bool(failFast), func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		return captureOnHost(sshArgs, host, string(cmd))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return starlark.NewList(results), nil
+}
+
+func captureOnHost(sshArgs crashdssh.SSHArgs, host, cmd string) *starlarkstruct.Struct {
+	client, err := crashdssh.NewClient(sshArgs)
+	if err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+	defer client.Close()
+
+	out, err := client.Exec(cmd)
+	if err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+
+	destDir := filepath.Join(defaults.workdir, sanitizeStr(host))
+	dest := filepath.Join(destDir, sanitizeStr(cmd)+".txt")
+
+	if err := writeCaptureFile(dest, out); err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+
+	return buildResultStruct(host, "", dest)
+}
+
+func writeCaptureFile(dest, content string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, []byte(content), 0644)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// resultStructOf extracts the (resource, err, result) fields a host-fanout
+// builtin (copy_from/capture/run_command) returns, mirroring the attribute
+// pulls in copy_from_test.go.
+func resultStructOf(t *testing.T, val starlark.Value) (resource, errMsg, result string) {
+	t.Helper()
+	strct, ok := val.(*starlarkstruct.Struct)
+	if !ok {
+		t.Fatalf("expected *starlarkstruct.Struct, got %T", val)
+	}
+	if v, err := strct.Attr("resource"); err == nil {
+		if s, ok := v.(starlark.String); ok {
+			resource = string(s)
+		}
+	}
+	if v, err := strct.Attr("err"); err == nil {
+		if s, ok := v.(starlark.String); ok {
+			errMsg = string(s)
+		}
+	}
+	if v, err := strct.Attr("result"); err == nil {
+		if s, ok := v.(starlark.String); ok {
+			result = string(s)
+		}
+	}
+	return resource, errMsg, result
+}
+
+func testCaptureFuncForHostResources(t *testing.T, port, privateKey, username string) {
+	b := starlark.NewBuiltin("capture", captureFunc)
+	sshCfg := makeTestSSHConfig(privateKey, port, username)
+	resources := starlark.NewList([]starlark.Value{makeTestSSHHostResource("127.0.0.1", sshCfg)})
+	kwargs := []starlark.Tuple{
+		{starlark.String("resources"), resources},
+	}
+	args := starlark.Tuple{starlark.String("echo FooBar")}
+
+	val, err := captureFunc(newTestThreadLocal(t), b, args, kwargs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource, cpErr, result := resultStructOf(t, val)
+	if cpErr != "" {
+		t.Fatal(cpErr)
+	}
+	defer os.RemoveAll(filepath.Join(defaults.workdir, sanitizeStr(resource)))
+
+	wantPath := filepath.Join(defaults.workdir, sanitizeStr(resource), sanitizeStr("echo FooBar")+".txt")
+	if result != wantPath {
+		t.Fatalf("unexpected captured file path: got %s, want %s", result, wantPath)
+	}
+
+	content, err := ioutil.ReadFile(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "FooBar\n" {
+		t.Fatalf("unexpected captured content: %q", content)
+	}
+}
+
+func testCaptureFuncMissingResources(t *testing.T, port, privateKey, username string) {
+	b := starlark.NewBuiltin("capture", captureFunc)
+	if _, err := captureFunc(newTestThreadLocal(t), b, starlark.Tuple{starlark.String("echo hi")}, nil); err == nil {
+		t.Fatal("expected an error when resources is missing")
+	}
+}
+
+func TestCaptureFuncSSHAll(t *testing.T) {
+	port := testSupport.PortValue()
+	username := testSupport.CurrentUsername()
+	privateKey := testSupport.PrivateKeyPath()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, port, privateKey, username string)
+	}{
+		{name: "capture func for host resources", test: testCaptureFuncForHostResources},
+		{name: "capture func with missing resources", test: testCaptureFuncMissingResources},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, port, privateKey, username)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+// resourceWork is run once per host resource by runOverResources. It must
+// build and return the struct (resource, err, result) describing the
+// outcome for that single host.
+type resourceWork func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct
+
+// runOverResources fans work out across resources, one goroutine per host,
+// bounded by concurrencyLimit(). Results are returned in the same order as
+// resources regardless of completion order. When failFast is true, the
+// first per-host error cancels ctx so workers that haven't started yet
+// short-circuit instead of dialing a doomed connection; workers already in
+// flight are not interrupted mid-transfer.
+func runOverResources(resources *starlark.List, failFast bool, work resourceWork) ([]starlark.Value, error) {
+	n := resources.Len()
+	results := make([]starlark.Value, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sem := make(chan struct{}, concurrencyLimit())
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		val := resources.Index(i)
+		strct, ok := val.(*starlarkstruct.Struct)
+		if !ok {
+			return nil, fmt.Errorf("copy_from/capture: expected a host resource at index %d, got %s", i, val.Type())
+		}
+
+		sshArgs, host, err := resourceSSHArgs(strct)
+		if err != nil {
+			return nil, err
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, sshArgs crashdssh.SSHArgs, host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			select {
+			case <-ctx.Done():
+				results[i] = buildResultStruct(host, "canceled: fail_fast", "")
+				return
+			default:
+			}
+
+			res := work(ctx, sshArgs, host)
+			results[i] = res
+
+			if failFast && resultHasErr(res) {
+				cancel()
+			}
+		}(i, sshArgs, host)
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+// buildResultStruct builds the (resource, err, result) struct returned to
+// Starlark for a single host, whether it came from copy_from or capture.
+func buildResultStruct(resource, errMsg, result string) *starlarkstruct.Struct {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"resource": starlark.String(resource),
+		"err":      starlark.String(errMsg),
+		"result":   starlark.String(result),
+	})
+}
+
+func resultHasErr(res *starlarkstruct.Struct) bool {
+	val, err := res.Attr("err")
+	if err != nil {
+		return false
+	}
+	s, ok := val.(starlark.String)
+	return ok && string(s) != ""
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+func resourcesOf(hosts ...string) *starlark.List {
+	sshCfg := makeTestSSHConfig("testkey", "22", "tester")
+	var vals []starlark.Value
+	for _, h := range hosts {
+		vals = append(vals, makeTestSSHHostResource(h, sshCfg))
+	}
+	return starlark.NewList(vals)
+}
+
+func TestRunOverResourcesOrdering(t *testing.T) {
+	hosts := []string{"h0", "h1", "h2", "h3", "h4"}
+	results, err := runOverResources(resourcesOf(hosts...), false, func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		// Sleep longest for the first host so it would finish last if
+		// results were ordered by completion instead of by index.
+		if host == "h0" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return buildResultStruct(host, "", host)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range hosts {
+		strct := results[i].(*starlarkstruct.Struct)
+		resVal, _ := strct.Attr("resource")
+		if string(resVal.(starlark.String)) != want {
+			t.Errorf("result[%d]: expected resource %s, got %s", i, want, resVal)
+		}
+	}
+}
+
+func TestRunOverResourcesConcurrencyLimit(t *testing.T) {
+	prev := defaults.concurrency
+	defaults.concurrency = 2
+	defer func() { defaults.concurrency = prev }()
+
+	var inFlight, maxInFlight int32
+	_, err := runOverResources(resourcesOf("h0", "h1", "h2", "h3", "h4", "h5"), false, func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		cur := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if cur <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return buildResultStruct(host, "", host)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 workers in flight, observed %d", maxInFlight)
+	}
+}
+
+func TestRunOverResourcesFailFast(t *testing.T) {
+	var started int32
+	results, err := runOverResources(resourcesOf("bad", "h1", "h2", "h3"), true, func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		atomic.AddInt32(&started, 1)
+		if host == "bad" {
+			return buildResultStruct(host, "boom", "")
+		}
+		// Give the "bad" worker a chance to cancel the context before
+		// the others would otherwise proceed.
+		time.Sleep(10 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return buildResultStruct(host, "canceled: fail_fast", "")
+		default:
+			return buildResultStruct(host, "", host)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceled := 0
+	for _, r := range results {
+		strct := r.(*starlarkstruct.Struct)
+		if resultHasErr(strct) {
+			errVal, _ := strct.Attr("err")
+			if string(errVal.(starlark.String)) == "canceled: fail_fast" {
+				canceled++
+			}
+		}
+	}
+	if canceled == 0 {
+		t.Errorf("expected at least one sibling to observe cancellation after fail_fast")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+// copyFromFunc implements the Starlark copy_from(path, resources=[...]) builtin.
+// Each resource is fetched concurrently, bounded by the concurrency set via
+// set_defaults(run_config(concurrency=N)) (default GOMAXPROCS). A single
+// resource yields a struct; more than one yields a *starlark.List of
+// structs indexed the same way resources was, regardless of which host
+// finished first. Pass fail_fast=True to stop dialing remaining hosts as
+// soon as one fails.
+//
+// With compress=True, the matched remote files are streamed straight into
+// <workdir>/<resource>.<ext> (compress_format "tgz", the default, or "zip"),
+// rather than landing in a scratch directory under <workdir>/<resource>/;
+// struct.result is the archive path. compress_level (0-9, default 6) is
+// only meaningful for "tgz".
+func copyFromFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		path           starlark.String
+		resourcesList  *starlark.List
+		failFast       starlark.Bool
+		compress       starlark.Bool
+		compressLevel  starlark.Int    = starlark.MakeInt(6)
+		compressFormat starlark.String = starlark.String("tgz")
+	)
+
+	if err := starlark.UnpackArgs(
+		b.Name(), args, kwargs,
+		"path?", &path,
+		"resources?", &resourcesList,
+		"fail_fast?", &failFast,
+		"compress?", &compress,
+		"compress_level?", &compressLevel,
+		"compress_format?", &compressFormat,
+	); err != nil {
+		return nil, err
+	}
+
+	if resourcesList == nil || resourcesList.Len() == 0 {
+		return nil, fmt.Errorf("%s: missing required resources", b.Name())
+	}
+
+	level, ok := compressLevel.Int64()
+	if !ok || level < 0 || level > 9 {
+		return nil, fmt.Errorf("%s: compress_level must be between 0 and 9", b.Name())
+	}
+	format := string(compressFormat)
+	if format != "tgz" && format != "zip" {
+		return nil, fmt.Errorf("%s: compress_format must be %q or %q", b.Name(), "tgz", "zip")
+	}
+
+	results, err := runOverResources(resourcesList, bool(failFast), func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		if compress {
+			return copyFromHostCompressed(sshArgs, host, string(path), format, int(level))
+		}
+		return copyFromHost(sshArgs, host, string(path))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return starlark.NewList(results), nil
+}
+
+func copyFromHost(sshArgs crashdssh.SSHArgs, host, path string) *starlarkstruct.Struct {
+	destDir := filepath.Join(defaults.workdir, sanitizeStr(host))
+
+	copied, err := crashdssh.CopyFrom(sshArgs, path, destDir)
+	if err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+
+	result := ""
+	if len(copied) > 0 {
+		result = copied[0]
+	}
+	return buildResultStruct(host, "", result)
+}
+
+func copyFromHostCompressed(sshArgs crashdssh.SSHArgs, host, path, format string, level int) *starlarkstruct.Struct {
+	ext := ".tar.gz"
+	if format == "zip" {
+		ext = ".zip"
+	}
+	archivePath := filepath.Join(defaults.workdir, sanitizeStr(host)+ext)
+
+	if err := crashdssh.CopyFromArchive(sshArgs, path, archivePath, format, level); err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+
+	return buildResultStruct(host, "", archivePath)
+}
@@ -4,7 +4,11 @@
 package starlark
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -118,12 +122,130 @@ func testCopyFromFuncForHostResources(t *testing.T, port, privateKey, username s
 					t.Fatal(cpErr)
 				}
 
-				expected := filepath.Join(defaults.workdir, sanitizeStr(resource), "foo.txt")
+				expected := filepath.Join(defaults.workdir, sanitizeStr(resource)+".tar.gz")
 				if result != expected {
-					t.Errorf("unexpected file name copied: %s", result)
+					t.Fatalf("expected archive %s, got %s", expected, result)
+				}
+				defer os.RemoveAll(expected)
+
+				f, err := os.Open(result)
+				if err != nil {
+					t.Fatalf("archive %s was not created: %s", result, err)
+				}
+				defer f.Close()
+
+				gzr, err := gzip.NewReader(f)
+				if err != nil {
+					t.Fatalf("archive %s is not a valid gzip stream: %s", result, err)
+				}
+				defer gzr.Close()
+
+				tr := tar.NewReader(gzr)
+				found := false
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("archive %s is not a valid tar stream: %s", result, err)
+					}
+					if filepath.Base(hdr.Name) != "foo.txt" {
+						continue
+					}
+					found = true
+					content, err := ioutil.ReadAll(tr)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if string(content) != "FooBar" {
+						t.Errorf("expected foo.txt content %q, got %q", "FooBar", string(content))
+					}
+				}
+				if !found {
+					t.Errorf("expected archive %s to contain foo.txt", result)
+				}
+			},
+		},
+		{
+			name:        "single machine compress zip",
+			remoteFiles: map[string]string{"foo.txt": "FooBar"},
+			args:        func(t *testing.T) starlark.Tuple { return starlark.Tuple{starlark.String("foo.txt")} },
+			kwargs: func(t *testing.T) []starlark.Tuple {
+				sshCfg := makeTestSSHConfig(privateKey, port, username)
+				resources := starlark.NewList([]starlark.Value{makeTestSSHHostResource("127.0.0.1", sshCfg)})
+				return []starlark.Tuple{
+					[]starlark.Value{starlark.String("resources"), resources},
+					[]starlark.Value{starlark.String("compress"), starlark.Bool(true)},
+					[]starlark.Value{starlark.String("compress_format"), starlark.String("zip")},
 				}
+			},
 
+			eval: func(t *testing.T, args starlark.Tuple, kwargs []starlark.Tuple) {
+
+				val, err := copyFromFunc(newTestThreadLocal(t), nil, args, kwargs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resource := ""
+				cpErr := ""
+				result := ""
+				if strct, ok := val.(*starlarkstruct.Struct); ok {
+					if val, err := strct.Attr("resource"); err == nil {
+						if r, ok := val.(starlark.String); ok {
+							resource = string(r)
+						}
+					}
+					if val, err := strct.Attr("err"); err == nil {
+						if r, ok := val.(starlark.String); ok {
+							cpErr = string(r)
+						}
+					}
+					if val, err := strct.Attr("result"); err == nil {
+						if r, ok := val.(starlark.String); ok {
+							result = string(r)
+						}
+					}
+				}
+
+				if cpErr != "" {
+					t.Fatal(cpErr)
+				}
+
+				expected := filepath.Join(defaults.workdir, sanitizeStr(resource)+".zip")
+				if result != expected {
+					t.Fatalf("expected archive %s, got %s", expected, result)
+				}
 				defer os.RemoveAll(expected)
+
+				zr, err := zip.OpenReader(result)
+				if err != nil {
+					t.Fatalf("archive %s is not a valid zip archive: %s", result, err)
+				}
+				defer zr.Close()
+
+				found := false
+				for _, zf := range zr.File {
+					if filepath.Base(zf.Name) != "foo.txt" {
+						continue
+					}
+					found = true
+					rc, err := zf.Open()
+					if err != nil {
+						t.Fatal(err)
+					}
+					content, err := ioutil.ReadAll(rc)
+					rc.Close()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if string(content) != "FooBar" {
+						t.Errorf("expected foo.txt content %q, got %q", "FooBar", string(content))
+					}
+				}
+				if !found {
+					t.Errorf("expected archive %s to contain foo.txt", result)
+				}
 			},
 		},
 		{
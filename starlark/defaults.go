@@ -0,0 +1,83 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"io/ioutil"
+	"runtime"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// execDefaults holds process-wide defaults that scripts can override via
+// set_defaults(). They are seeded once at package init and mutated by
+// setDefaultsFunc as scripts execute.
+type execDefaults struct {
+	workdir     string
+	concurrency int
+}
+
+// defaults is shared by every Executor in the process. Scripts run
+// sequentially today, so a package-level value is sufficient and mirrors
+// how workdir has always been handled.
+var defaults = newExecDefaults()
+
+func newExecDefaults() *execDefaults {
+	dir, err := ioutil.TempDir("", "crashd-")
+	if err != nil {
+		dir = "/tmp/crashd"
+	}
+	return &execDefaults{
+		workdir:     dir,
+		concurrency: runtime.GOMAXPROCS(0),
+	}
+}
+
+// runConfigFunc implements the Starlark run_config(...) constructor used
+// inside set_defaults() to tune how crashd executes work against resources.
+//
+//	set_defaults(run_config(concurrency=4))
+func runConfigFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var concurrency starlark.Int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "concurrency?", &concurrency); err != nil {
+		return nil, err
+	}
+
+	fields := starlark.StringDict{}
+	if val, ok := concurrency.Int64(); ok && val > 0 {
+		fields["concurrency"] = concurrency
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields), nil
+}
+
+// setDefaultsFunc implements the Starlark set_defaults(...) builtin. It
+// applies the concurrency field of any struct argument (as built by
+// run_config()) to the shared defaults. Other struct fields, such as those
+// of ssh_config(), and non-struct arguments, such as the list returned by
+// resources(), are ignored.
+func setDefaultsFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	for _, arg := range args {
+		strct, ok := arg.(*starlarkstruct.Struct)
+		if !ok {
+			continue
+		}
+		if val, err := strct.Attr("concurrency"); err == nil {
+			if n, ok := val.(starlark.Int); ok {
+				if i, ok := n.Int64(); ok && i > 0 {
+					defaults.concurrency = int(i)
+				}
+			}
+		}
+	}
+	return starlark.None, nil
+}
+
+func concurrencyLimit() int {
+	if defaults.concurrency < 1 {
+		return 1
+	}
+	return defaults.concurrency
+}
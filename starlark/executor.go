@@ -0,0 +1,149 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package starlark exposes crash-diagnostics' diagnostic script language, a
+// small set of Starlark builtins (ssh_config, resources, copy_from,
+// capture, archive, ...) layered on top of go.starlark.net.
+package starlark
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// builtins is the set of top-level functions exposed to every script
+// executed by an Executor.
+var builtins = starlark.StringDict{
+	"ssh_config":         starlark.NewBuiltin("ssh_config", sshConfigFunc),
+	"host_list_provider": starlark.NewBuiltin("host_list_provider", hostListProviderFunc),
+	"resources":          starlark.NewBuiltin("resources", resourcesFunc),
+	"set_defaults":       starlark.NewBuiltin("set_defaults", setDefaultsFunc),
+	"run_config":         starlark.NewBuiltin("run_config", runConfigFunc),
+	"copy_from":          starlark.NewBuiltin("copy_from", copyFromFunc),
+	"capture":            starlark.NewBuiltin("capture", captureFunc),
+	"run_command":        starlark.NewBuiltin("run_command", runCommandFunc),
+	"archive":            starlark.NewBuiltin("archive", archiveFunc),
+}
+
+// Executor runs crash-diagnostics scripts and collects the values bound to
+// their top-level variables. An Executor can run more than one script (via
+// successive Exec calls) and caches `load()`-ed modules across those calls.
+type Executor struct {
+	thread       *starlark.Thread
+	result       map[string]starlark.Value
+	libraryPaths []string
+	cache        *loadCache
+	secrets      *secretStore
+	env          *starlark.Dict
+}
+
+// Option configures an Executor at construction time.
+type Option func(*Executor)
+
+// WithLibraryPath adds one or more directories to the search path used to
+// resolve load() statements, in addition to CRASHD_LIB_PATH (colon
+// separated) and the directory of the file doing the loading.
+func WithLibraryPath(paths ...string) Option {
+	return func(exe *Executor) {
+		exe.libraryPaths = append(exe.libraryPaths, paths...)
+	}
+}
+
+// WithEnv binds env as the predeclared "env" dict available to script
+// bodies, letting them do ssh_config(username=env["SSH_USER"]) instead of
+// hard-coding values. Pass the dict returned by a parsed script.Script's
+// StarlarkEnv, which resolves a script's ENV/ENV_FILE preamble.
+func WithEnv(env *starlark.Dict) Option {
+	return func(exe *Executor) {
+		exe.env = env
+	}
+}
+
+// New creates an Executor ready to run a script via Exec.
+func New(opts ...Option) *Executor {
+	exe := &Executor{
+		result:  map[string]starlark.Value{},
+		cache:   newLoadCache(),
+		secrets: newSecretStore(),
+	}
+
+	if env := os.Getenv("CRASHD_LIB_PATH"); env != "" {
+		exe.libraryPaths = append(exe.libraryPaths, strings.Split(env, string(filepath.ListSeparator))...)
+	}
+
+	for _, opt := range opts {
+		opt(exe)
+	}
+
+	exe.thread = &starlark.Thread{
+		Name: "crashd",
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Println(msg)
+		},
+		Load: exe.load,
+	}
+
+	return exe
+}
+
+// predeclared returns the builtins available to a script, including the
+// crashd_load_cache() hook bound to this Executor's cache.
+func (exe *Executor) predeclared() starlark.StringDict {
+	predeclared := make(starlark.StringDict, len(builtins)+1)
+	for k, v := range builtins {
+		predeclared[k] = v
+	}
+	predeclared["crashd_load_cache"] = starlark.NewBuiltin("crashd_load_cache", exe.loadCacheFunc)
+	predeclared["prompt_secret"] = starlark.NewBuiltin("prompt_secret", exe.promptSecretFunc)
+	if exe.env != nil {
+		predeclared["env"] = exe.env
+	}
+	return predeclared
+}
+
+// Close releases resources held by the Executor, clearing any secrets
+// obtained via prompt_secret() from memory.
+func (exe *Executor) Close() error {
+	exe.secrets.clearAll()
+	return nil
+}
+
+// Exec parses and evaluates the script read from source, named name for
+// error messages. Top-level bindings are made available via exe.result.
+func (exe *Executor) Exec(name string, source io.Reader) error {
+	data, err := ioutil.ReadAll(source)
+	if err != nil {
+		return fmt.Errorf("starlark: failed to read %s: %w", name, err)
+	}
+
+	globals, err := starlark.ExecFile(exe.thread, name, data, exe.predeclared())
+	if err != nil {
+		return fmt.Errorf("starlark: failed to execute %s: %w", name, err)
+	}
+
+	for k, v := range globals {
+		exe.result[k] = v
+	}
+
+	return nil
+}
+
+// loadCacheFunc implements the crashd_load_cache(reset=True) test hook,
+// letting tests force modules to be re-resolved/re-executed on the next
+// load() instead of served from cache.
+func (exe *Executor) loadCacheFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var reset starlark.Bool
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "reset?", &reset); err != nil {
+		return nil, err
+	}
+	if reset {
+		exe.cache.reset()
+	}
+	return starlark.None, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// testSupportType centralizes the knobs needed to run the SSH-backed tests
+// in this package against a local sshd, overridable via env vars so CI can
+// point at a throwaway container.
+type testSupportType struct{}
+
+var testSupport testSupportType
+
+func (testSupportType) PortValue() string {
+	if p := os.Getenv("CRASHD_TEST_SSH_PORT"); p != "" {
+		return p
+	}
+	return "22"
+}
+
+func (testSupportType) CurrentUsername() string {
+	if u := os.Getenv("CRASHD_TEST_SSH_USER"); u != "" {
+		return u
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "root"
+}
+
+func (testSupportType) PrivateKeyPath() string {
+	if p := os.Getenv("CRASHD_TEST_SSH_KEY"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ssh", "id_rsa")
+}
+
+func (testSupportType) MaxConnectionRetries() int {
+	return 3
+}
+
+func newTestThreadLocal(t *testing.T) *starlark.Thread {
+	t.Helper()
+	return &starlark.Thread{Name: t.Name()}
+}
+
+func makeTestSSHConfig(privateKey, port, username string) *starlarkstruct.Struct {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"username":         starlark.String(username),
+		"port":             starlark.String(port),
+		"private_key_path": starlark.String(privateKey),
+		"max_retries":      starlark.MakeInt(testSupport.MaxConnectionRetries()),
+	})
+}
+
+func makeTestSSHHostResource(host string, sshCfg *starlarkstruct.Struct) starlark.Value {
+	return newHostResource(host, sshCfg)
+}
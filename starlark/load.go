@@ -0,0 +1,155 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// builtinModules are library modules shipped with crash-diagnostics and
+// addressable as load("@crashd//<name>.star", ...) regardless of
+// WithLibraryPath/CRASHD_LIB_PATH. They exist so common ssh_config/resource
+// recipes don't have to be copy-pasted between scripts.
+var builtinModules = map[string]string{
+	"@crashd//os.star": `
+def common_ssh_config(username, private_key_path, port="22"):
+    return ssh_config(username=username, private_key_path=private_key_path, port=port)
+`,
+	"@crashd//k8s.star": `
+def control_plane_hosts(hosts, ssh_cfg):
+    return resources(provider=host_list_provider(hosts=hosts, ssh_config=ssh_cfg))
+`,
+}
+
+// cachedModule is one entry in a loadCache: the globals produced by
+// executing a module, plus enough to know whether it needs re-executing.
+type cachedModule struct {
+	globals starlark.StringDict
+	mtime   time.Time // zero for in-memory builtin modules, which never change
+}
+
+// loadCache memoizes module resolution/execution per Executor so that
+// diamond loads (A and B both load C) only execute C once, and so that
+// editing a library file on disk invalidates just that file.
+type loadCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedModule
+}
+
+func newLoadCache() *loadCache {
+	return &loadCache{entries: map[string]*cachedModule{}}
+}
+
+func (c *loadCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*cachedModule{}
+}
+
+func (c *loadCache) get(key string, mtime time.Time) (starlark.StringDict, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.mtime.IsZero() && !entry.mtime.Equal(mtime) {
+		return nil, false
+	}
+	return entry.globals, true
+}
+
+func (c *loadCache) put(key string, mtime time.Time, globals starlark.StringDict) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cachedModule{globals: globals, mtime: mtime}
+}
+
+// load is installed as the Executor's thread.Load callback. It resolves
+// module in this order: crash-diagnostics' own built-in library modules
+// (the @crashd// names), library roots (WithLibraryPath / CRASHD_LIB_PATH),
+// then a path relative to the file currently being executed.
+func (exe *Executor) load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	if src, ok := builtinModules[module]; ok {
+		if globals, ok := exe.cache.get(module, time.Time{}); ok {
+			return globals, nil
+		}
+		globals, err := exe.execModule(module, []byte(src))
+		if err != nil {
+			return nil, err
+		}
+		exe.cache.put(module, time.Time{}, globals)
+		return globals, nil
+	}
+
+	path, err := exe.resolveModulePath(thread, module)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("load(%q): %w", module, err)
+	}
+
+	if globals, ok := exe.cache.get(path, info.ModTime()); ok {
+		return globals, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load(%q): %w", module, err)
+	}
+
+	globals, err := exe.execModule(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	exe.cache.put(path, info.ModTime(), globals)
+	return globals, nil
+}
+
+// resolveModulePath looks for module relative to each configured library
+// root, then relative to the directory of the file that contains the
+// load() statement.
+func (exe *Executor) resolveModulePath(thread *starlark.Thread, module string) (string, error) {
+	candidates := make([]string, 0, len(exe.libraryPaths)+1)
+	for _, root := range exe.libraryPaths {
+		candidates = append(candidates, filepath.Join(root, module))
+	}
+
+	if caller := thread.CallFrame(0); caller.Pos.Filename() != "" {
+		candidates = append(candidates, filepath.Join(filepath.Dir(caller.Pos.Filename()), module))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("load(%q): module not found in library path or relative to caller", module)
+}
+
+// execModule runs a loaded module's source in a fresh sub-thread (sharing
+// this Executor's Load callback so transitive loads also get cached) and
+// freezes the resulting globals so one module's mutations can't leak into
+// another script that loaded it.
+func (exe *Executor) execModule(name string, data []byte) (starlark.StringDict, error) {
+	thread := &starlark.Thread{Name: name, Load: exe.load}
+	globals, err := starlark.ExecFile(thread, name, data, exe.predeclared())
+	if err != nil {
+		return nil, fmt.Errorf("load(%q): %w", name, err)
+	}
+	globals.Freeze()
+	return globals, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestLoadFromLibraryPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashd-libpath-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, "hosts.star")
+	if err := ioutil.WriteFile(lib, []byte(`NAME = "shared-hosts"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exe := New(WithLibraryPath(dir))
+	script := `load("hosts.star", "NAME")` + "\n" + `result = NAME`
+	if err := exe.Exec("test.star", strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := exe.result["result"].(starlark.String)
+	if !ok || string(got) != "shared-hosts" {
+		t.Errorf("expected result %q, got %v", "shared-hosts", exe.result["result"])
+	}
+}
+
+func TestLoadBuiltinModule(t *testing.T) {
+	exe := New()
+	script := `load("@crashd//os.star", "common_ssh_config")
+result = common_ssh_config(username="op", private_key_path="/tmp/key")`
+
+	if err := exe.Exec("test.star", strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	if exe.result["result"] == nil {
+		t.Fatal("expected common_ssh_config(...) to be assigned to result")
+	}
+}
+
+func TestLoadCachesDiamondLoads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashd-libpath-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shared := filepath.Join(dir, "shared.star")
+	if err := ioutil.WriteFile(shared, []byte(`COUNT = 1`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "a.star")
+	if err := ioutil.WriteFile(a, []byte(`load("shared.star", "COUNT")`+"\n"+`A_COUNT = COUNT`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := filepath.Join(dir, "b.star")
+	if err := ioutil.WriteFile(b, []byte(`load("shared.star", "COUNT")`+"\n"+`B_COUNT = COUNT`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exe := New(WithLibraryPath(dir))
+	script := `load("a.star", "A_COUNT")
+load("b.star", "B_COUNT")
+result = A_COUNT + B_COUNT`
+	if err := exe.Exec("test.star", strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := exe.cache.entries[shared]; !ok {
+		t.Fatal("expected shared.star to be recorded in the load cache")
+	}
+}
+
+func TestLoadCacheResetHook(t *testing.T) {
+	exe := New()
+	script := `crashd_load_cache(reset=True)`
+	if err := exe.Exec("test.star", strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	if len(exe.cache.entries) != 0 {
+		t.Errorf("expected cache to be empty after reset, got %d entries", len(exe.cache.entries))
+	}
+}
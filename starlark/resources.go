@@ -0,0 +1,106 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"regexp"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+var sanitizePattern = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// sanitizeStr turns an arbitrary resource name (a hostname, an IP, a pod
+// name) into something that is safe to use as a path component under
+// defaults.workdir.
+func sanitizeStr(s string) string {
+	return sanitizePattern.ReplaceAllString(s, "_")
+}
+
+// newHostResource builds the Starlark struct used throughout the starlark
+// package to represent a single SSH-reachable host paired with the
+// ssh_config() used to reach it.
+func newHostResource(host string, sshCfg *starlarkstruct.Struct) *starlarkstruct.Struct {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"host":       starlark.String(host),
+		"ssh_config": sshCfg,
+	})
+}
+
+// hostListProviderFunc implements host_list_provider(hosts=[...], ssh_config=...).
+func hostListProviderFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		hosts  *starlark.List
+		sshCfg *starlarkstruct.Struct
+	)
+
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "hosts", &hosts, "ssh_config?", &sshCfg); err != nil {
+		return nil, err
+	}
+
+	var resources []starlark.Value
+	if hosts != nil {
+		iter := hosts.Iterate()
+		defer iter.Done()
+		var val starlark.Value
+		for iter.Next(&val) {
+			resources = append(resources, newHostResource(asString(val), sshCfg))
+		}
+	}
+
+	return starlark.NewList(resources), nil
+}
+
+// resourcesFunc implements resources(provider=host_list_provider(...)).
+func resourcesFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var provider starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "provider", &provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// resourceSSHArgs extracts ssh.SSHArgs from a host resource struct produced
+// by newHostResource.
+func resourceSSHArgs(res *starlarkstruct.Struct) (crashdssh.SSHArgs, string, error) {
+	hostVal, err := res.Attr("host")
+	if err != nil {
+		return crashdssh.SSHArgs{}, "", err
+	}
+	host := asString(hostVal)
+
+	args := crashdssh.SSHArgs{Host: host, Port: "22", MaxRetries: 3}
+
+	cfgVal, err := res.Attr("ssh_config")
+	if err == nil {
+		if cfg, ok := cfgVal.(*starlarkstruct.Struct); ok {
+			if v, err := cfg.Attr("username"); err == nil {
+				args.User = asString(v)
+			}
+			if v, err := cfg.Attr("port"); err == nil {
+				if p := asString(v); p != "" {
+					args.Port = p
+				}
+			}
+			if v, err := cfg.Attr("private_key_path"); err == nil {
+				args.PrivateKeyPath = asString(v)
+			}
+			if v, err := cfg.Attr("private_key_passphrase"); err == nil {
+				args.PrivateKeyPassphrase = secretBytes(v)
+			}
+			if v, err := cfg.Attr("max_retries"); err == nil {
+				if n, ok := v.(starlark.Int); ok {
+					if i, ok := n.Int64(); ok {
+						args.MaxRetries = int(i)
+					}
+				}
+			}
+		}
+	}
+
+	return args, host, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"context"
+	"fmt"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+
+	crashdssh "github.com/vmware-tanzu/crash-diagnostics/ssh"
+)
+
+// runCommandFunc implements the Starlark run_command(cmd, resources=[...],
+// sudo=False, sudo_password=None) builtin. With sudo=True, cmd is run via
+// `sudo -S` over an allocated pty and sudo_password (normally
+// prompt_secret("sudo")) is written to the remote sudo prompt the moment it
+// appears, rather than ever touching disk or exe.result.
+func runCommandFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		cmd           starlark.String
+		resourcesList *starlark.List
+		sudo          starlark.Bool
+		sudoPassword  starlark.Value = starlark.None
+		failFast      starlark.Bool
+	)
+
+	if err := starlark.UnpackArgs(
+		b.Name(), args, kwargs,
+		"cmd?", &cmd,
+		"resources?", &resourcesList,
+		"sudo?", &sudo,
+		"sudo_password?", &sudoPassword,
+		"fail_fast?", &failFast,
+	); err != nil {
+		return nil, err
+	}
+
+	if resourcesList == nil || resourcesList.Len() == 0 {
+		return nil, fmt.Errorf("%s: missing required resources", b.Name())
+	}
+
+	if sudo && secretBytes(sudoPassword) == nil {
+		return nil, fmt.Errorf("%s: sudo=True requires sudo_password=prompt_secret(...)", b.Name())
+	}
+	password := secretBytes(sudoPassword)
+
+	results, err := runOverResources(resourcesList, bool(failFast), func(ctx context.Context, sshArgs crashdssh.SSHArgs, host string) *starlarkstruct.Struct {
+		return runCommandOnHost(sshArgs, host, string(cmd), bool(sudo), password)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return starlark.NewList(results), nil
+}
+
+func runCommandOnHost(sshArgs crashdssh.SSHArgs, host, cmd string, sudo bool, sudoPassword []byte) *starlarkstruct.Struct {
+	if sudo {
+		out, err := crashdssh.RunSudo(sshArgs, cmd, sudoPassword)
+		if err != nil {
+			return buildResultStruct(host, err.Error(), "")
+		}
+		return buildResultStruct(host, "", out)
+	}
+
+	client, err := crashdssh.NewClient(sshArgs)
+	if err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+	defer client.Close()
+
+	out, err := client.Exec(cmd)
+	if err != nil {
+		return buildResultStruct(host, err.Error(), "")
+	}
+	return buildResultStruct(host, "", out)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func testRunCommandFuncNonSudo(t *testing.T, port, privateKey, username string) {
+	b := starlark.NewBuiltin("run_command", runCommandFunc)
+	sshCfg := makeTestSSHConfig(privateKey, port, username)
+	resources := starlark.NewList([]starlark.Value{makeTestSSHHostResource("127.0.0.1", sshCfg)})
+	kwargs := []starlark.Tuple{
+		{starlark.String("resources"), resources},
+	}
+	args := starlark.Tuple{starlark.String("echo FooBar")}
+
+	val, err := runCommandFunc(newTestThreadLocal(t), b, args, kwargs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmdErr, result := resultStructOf(t, val)
+	if cmdErr != "" {
+		t.Fatal(cmdErr)
+	}
+	if result != "FooBar\n" {
+		t.Fatalf("unexpected output: %q", result)
+	}
+}
+
+func testRunCommandFuncSudoRequiresSecret(t *testing.T, port, privateKey, username string) {
+	b := starlark.NewBuiltin("run_command", runCommandFunc)
+	sshCfg := makeTestSSHConfig(privateKey, port, username)
+	resources := starlark.NewList([]starlark.Value{makeTestSSHHostResource("127.0.0.1", sshCfg)})
+	kwargs := []starlark.Tuple{
+		{starlark.String("resources"), resources},
+		{starlark.String("sudo"), starlark.Bool(true)},
+	}
+	args := starlark.Tuple{starlark.String("whoami")}
+
+	_, err := runCommandFunc(newTestThreadLocal(t), b, args, kwargs)
+	if err == nil {
+		t.Fatal("expected an error when sudo=True is set without sudo_password")
+	}
+	wantErr := "run_command: sudo=True requires sudo_password=prompt_secret(...)"
+	if err.Error() != wantErr {
+		t.Fatalf("expected error %q, got %q", wantErr, err.Error())
+	}
+}
+
+func testRunCommandFuncSudoWithSecret(t *testing.T, port, privateKey, username string) {
+	b := starlark.NewBuiltin("run_command", runCommandFunc)
+	sshCfg := makeTestSSHConfig(privateKey, port, username)
+	resources := starlark.NewList([]starlark.Value{makeTestSSHHostResource("127.0.0.1", sshCfg)})
+	secret := &Secret{name: "sudo", value: []byte("definitely-wrong-password")}
+	kwargs := []starlark.Tuple{
+		{starlark.String("resources"), resources},
+		{starlark.String("sudo"), starlark.Bool(true)},
+		{starlark.String("sudo_password"), secret},
+	}
+	args := starlark.Tuple{starlark.String("whoami")}
+
+	val, err := runCommandFunc(newTestThreadLocal(t), b, args, kwargs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A wrong password can't be expected to succeed against a real sudo
+	// prompt, but reaching a per-host err (rather than the validation error
+	// above) confirms sudo_password was unwrapped and plumbed into
+	// ssh.RunSudo rather than rejected up front.
+	_, cmdErr, _ := resultStructOf(t, val)
+	if cmdErr == "" {
+		t.Fatal("expected sudo with a wrong password to fail on the remote prompt")
+	}
+	if !strings.Contains(cmdErr, "sudo") {
+		t.Fatalf("expected the failure to come from the sudo path, got %q", cmdErr)
+	}
+}
+
+func TestRunCommandFuncSSHAll(t *testing.T) {
+	port := testSupport.PortValue()
+	username := testSupport.CurrentUsername()
+	privateKey := testSupport.PrivateKeyPath()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, port, privateKey, username string)
+	}{
+		{name: "run_command without sudo", test: testRunCommandFuncNonSudo},
+		{name: "run_command sudo=True without sudo_password errors", test: testRunCommandFuncSudoRequiresSecret},
+		{name: "run_command sudo=True with sudo_password reaches the remote sudo prompt", test: testRunCommandFuncSudoWithSecret},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, port, privateKey, username)
+		})
+	}
+}
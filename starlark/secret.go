@@ -0,0 +1,143 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"golang.org/x/term"
+)
+
+// Secret is an opaque Starlark value holding sensitive data (an SSH key
+// passphrase, a sudo password) obtained via prompt_secret(). It deliberately
+// refuses to print its contents so a secret can't end up in exe.result,
+// error messages, or an archive.
+type Secret struct {
+	name  string
+	value []byte
+}
+
+var _ starlark.Value = (*Secret)(nil)
+
+func (s *Secret) String() string        { return "<secret>" }
+func (s *Secret) Type() string          { return "secret" }
+func (s *Secret) Freeze()               {}
+func (s *Secret) Truth() starlark.Bool  { return len(s.value) > 0 }
+func (s *Secret) Hash() (uint32, error) { return 0, fmt.Errorf("secret: unhashable type") }
+
+// reveal returns the underlying bytes. Only internal callers that must
+// actually use the secret (building ssh.SSHArgs, writing to a sudo prompt)
+// may call it.
+func (s *Secret) reveal() []byte { return s.value }
+
+// clear zeroes the secret's backing bytes so they don't linger in memory
+// after the executor is done with them.
+func (s *Secret) clear() {
+	for i := range s.value {
+		s.value[i] = 0
+	}
+}
+
+// secretBytes extracts the raw bytes from a Starlark value produced by
+// prompt_secret(), or nil if v isn't a *Secret.
+func secretBytes(v starlark.Value) []byte {
+	if s, ok := v.(*Secret); ok {
+		return s.reveal()
+	}
+	return nil
+}
+
+// secretStore tracks every Secret minted by an Executor so they can all be
+// wiped from memory on shutdown, and caches prompted secrets by name so a
+// script that references prompt_secret("sudo") twice only prompts once.
+type secretStore struct {
+	mu       sync.Mutex
+	secrets  map[string]*Secret
+	uncached []*Secret
+}
+
+func newSecretStore() *secretStore {
+	return &secretStore{secrets: map[string]*Secret{}}
+}
+
+func (s *secretStore) clearAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, secret := range s.secrets {
+		secret.clear()
+	}
+	for _, secret := range s.uncached {
+		secret.clear()
+	}
+	s.secrets = map[string]*Secret{}
+	s.uncached = nil
+}
+
+// promptSecretFunc implements the Starlark prompt_secret(name, message,
+// cache=True) builtin, bound to exe so prompted values can be cached and
+// later cleared.
+func (exe *Executor) promptSecretFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		name    starlark.String
+		message starlark.String
+		cache   starlark.Bool = true
+	)
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "message?", &message, "cache?", &cache); err != nil {
+		return nil, err
+	}
+
+	key := string(name)
+	if cache {
+		exe.secrets.mu.Lock()
+		if existing, ok := exe.secrets.secrets[key]; ok {
+			exe.secrets.mu.Unlock()
+			return existing, nil
+		}
+		exe.secrets.mu.Unlock()
+	}
+
+	value, err := readSecret(key, string(message))
+	if err != nil {
+		return nil, fmt.Errorf("prompt_secret(%q): %w", key, err)
+	}
+
+	secret := &Secret{name: key, value: value}
+	exe.secrets.mu.Lock()
+	if cache {
+		exe.secrets.secrets[key] = secret
+	} else {
+		exe.secrets.uncached = append(exe.secrets.uncached, secret)
+	}
+	exe.secrets.mu.Unlock()
+	return secret, nil
+}
+
+// readSecret reads a secret interactively when attached to a TTY, echo
+// disabled, and otherwise falls back to CRASHD_SECRET_<NAME> from the
+// process environment.
+func readSecret(name, message string) ([]byte, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		if message == "" {
+			message = fmt.Sprintf("Enter value for %s: ", name)
+		}
+		fmt.Fprint(os.Stderr, message)
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret from terminal: %w", err)
+		}
+		return value, nil
+	}
+
+	envName := "CRASHD_SECRET_" + strings.ToUpper(name)
+	value, ok := os.LookupEnv(envName)
+	if !ok {
+		return nil, fmt.Errorf("not attached to a terminal and %s is not set", envName)
+	}
+	return []byte(value), nil
+}
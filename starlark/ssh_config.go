@@ -0,0 +1,54 @@
+// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package starlark
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// sshConfigFunc implements the Starlark ssh_config(...) constructor.
+//
+//	ssh_config(username="user", port="22", private_key_path="/path/to/key")
+func sshConfigFunc(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		username             starlark.String
+		port                 starlark.String = starlark.String("22")
+		privateKeyPath       starlark.String
+		privateKeyPassphrase starlark.Value = starlark.None
+		maxRetries           starlark.Int   = starlark.MakeInt(3)
+	)
+
+	if err := starlark.UnpackArgs(
+		b.Name(), args, kwargs,
+		"username?", &username,
+		"port?", &port,
+		"private_key_path?", &privateKeyPath,
+		"private_key_passphrase?", &privateKeyPassphrase,
+		"max_retries?", &maxRetries,
+	); err != nil {
+		return nil, err
+	}
+
+	if _, ok := privateKeyPassphrase.(*Secret); privateKeyPassphrase != starlark.None && !ok {
+		return nil, fmt.Errorf("%s: private_key_passphrase must come from prompt_secret(), got %s", b.Name(), privateKeyPassphrase.Type())
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"username":               username,
+		"port":                   port,
+		"private_key_path":       privateKeyPath,
+		"private_key_passphrase": privateKeyPassphrase,
+		"max_retries":            maxRetries,
+	}), nil
+}
+
+func asString(val starlark.Value) string {
+	if s, ok := val.(starlark.String); ok {
+		return string(s)
+	}
+	return ""
+}